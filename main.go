@@ -2,8 +2,11 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net/http"
 	"net/url"
 	"os"
 	"strconv"
@@ -15,22 +18,51 @@ import (
 	"github.com/sensu/nginx-check/nginx"
 	"github.com/sensu/sensu-go/types"
 	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"gopkg.in/yaml.v3"
 )
 
 // Config represents the check plugin config.
 type Config struct {
 	sensu.PluginConfig
-	hostname   string
-	port       uint32
-	statusPath string
-	url        string
-	timeout    uint32
+	hostname    string
+	port        uint32
+	statusPath  string
+	urls        []string
+	targets     []string
+	targetsFile string
+	timeout     uint32
+	mode        string
+	format      string
+	alias       string
+	concurrency uint32
+
+	caCert                string
+	clientCert            string
+	clientKey             string
+	insecureSkipVerify    bool
+	basicAuthUser         string
+	basicAuthPassword     string
+	basicAuthPasswordFile string
+	headers               []string
+
+	stateFile       string
+	maxActive       float64
+	minRequestsRate float64
+	maxWaitingRatio float64
+}
+
+// targetSpec is the shape of each entry in a --targets-file (YAML or JSON).
+type targetSpec struct {
+	Hostname string `json:"hostname" yaml:"hostname"`
+	Port     uint32 `json:"port" yaml:"port"`
+	Path     string `json:"path" yaml:"path"`
+	URL      string `json:"url" yaml:"url"`
 }
 
 var (
-	nginxUrl      string
-	nginxHostname string
-	nginxPort     string
+	nginxTargets []nginx.Target
+	nginxClient  *http.Client
+	nginxHeaders map[string]string
 
 	plugin = Config{
 		PluginConfig: sensu.PluginConfig{
@@ -70,9 +102,25 @@ var (
 			Env:       "NGINX_CHECK_URL",
 			Argument:  "url",
 			Shorthand: "u",
+			Default:   []string{},
+			Usage:     "The NGINX status path URL; repeat to scrape several targets",
+			Value:     &plugin.urls,
+		}, {
+			Path:      "target",
+			Env:       "NGINX_CHECK_TARGET",
+			Argument:  "target",
+			Shorthand: "",
+			Default:   []string{},
+			Usage:     "A target to scrape, as \"host:port:path\" (path defaults to --status-path); repeat for several targets",
+			Value:     &plugin.targets,
+		}, {
+			Path:      "targets-file",
+			Env:       "NGINX_CHECK_TARGETS_FILE",
+			Argument:  "targets-file",
+			Shorthand: "",
 			Default:   "",
-			Usage:     "The NGINX status path URL",
-			Value:     &plugin.url,
+			Usage:     "A YAML or JSON file listing targets to scrape, as a list of {hostname, port, path} or {url}",
+			Value:     &plugin.targetsFile,
 		}, {
 			Path:      "timeout",
 			Env:       "NGINX_CHECK_TIMEOUT",
@@ -81,6 +129,135 @@ var (
 			Default:   uint32(10),
 			Usage:     "The request timeout in seconds (0 for no timeout)",
 			Value:     &plugin.timeout,
+		}, {
+			Path:      "concurrency",
+			Env:       "NGINX_CHECK_CONCURRENCY",
+			Argument:  "concurrency",
+			Shorthand: "",
+			Default:   uint32(nginx.DefaultConcurrency),
+			Usage:     "The maximum number of targets to scrape concurrently",
+			Value:     &plugin.concurrency,
+		}, {
+			Path:      "mode",
+			Env:       "NGINX_CHECK_MODE",
+			Argument:  "mode",
+			Shorthand: "m",
+			Default:   nginx.ModeStub,
+			Usage:     "The NGINX status mode: \"stub\" for stub_status or \"plus\" for the NGINX Plus API",
+			Value:     &plugin.mode,
+		}, {
+			Path:      "format",
+			Env:       "NGINX_CHECK_FORMAT",
+			Argument:  "format",
+			Shorthand: "",
+			Default:   nginx.FormatAuto,
+			Usage:     "The status response format when mode is \"stub\": \"auto\", \"stub\", \"vts\" or \"lua-prometheus\"",
+			Value:     &plugin.format,
+		}, {
+			Path:      "alias",
+			Env:       "NGINX_CHECK_ALIAS",
+			Argument:  "alias",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "An alias for this instance, prefixed on log messages and added as an \"alias\" metric label, to tell several instances apart",
+			Value:     &plugin.alias,
+		}, {
+			Path:      "ca-cert",
+			Env:       "NGINX_CHECK_CA_CERT",
+			Argument:  "ca-cert",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "Path to a PEM CA certificate to trust, for status endpoints using a self-signed certificate",
+			Value:     &plugin.caCert,
+		}, {
+			Path:      "client-cert",
+			Env:       "NGINX_CHECK_CLIENT_CERT",
+			Argument:  "client-cert",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "Path to a PEM client certificate, for status endpoints requiring mTLS",
+			Value:     &plugin.clientCert,
+		}, {
+			Path:      "client-key",
+			Env:       "NGINX_CHECK_CLIENT_KEY",
+			Argument:  "client-key",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "Path to the PEM key matching --client-cert",
+			Value:     &plugin.clientKey,
+		}, {
+			Path:      "insecure-skip-verify",
+			Env:       "NGINX_CHECK_INSECURE_SKIP_VERIFY",
+			Argument:  "insecure-skip-verify",
+			Shorthand: "",
+			Default:   false,
+			Usage:     "Skip verification of the status endpoint's TLS certificate",
+			Value:     &plugin.insecureSkipVerify,
+		}, {
+			Path:      "basic-auth-user",
+			Env:       "NGINX_CHECK_BASIC_AUTH_USER",
+			Argument:  "basic-auth-user",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "Username for HTTP basic auth against the status endpoint",
+			Value:     &plugin.basicAuthUser,
+		}, {
+			Path:      "basic-auth-password",
+			Env:       "NGINX_CHECK_BASIC_AUTH_PASSWORD",
+			Argument:  "basic-auth-password",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "Password for HTTP basic auth against the status endpoint",
+			Secret:    true,
+			Value:     &plugin.basicAuthPassword,
+		}, {
+			Path:      "basic-auth-password-file",
+			Env:       "NGINX_CHECK_BASIC_AUTH_PASSWORD_FILE",
+			Argument:  "basic-auth-password-file",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "Path to a file holding the HTTP basic auth password, as an alternative to --basic-auth-password",
+			Value:     &plugin.basicAuthPasswordFile,
+		}, {
+			Path:      "header",
+			Env:       "NGINX_CHECK_HEADERS",
+			Argument:  "header",
+			Shorthand: "",
+			Default:   []string{},
+			Usage:     "An extra \"key=value\" request header to send to the status endpoint; repeat for several headers",
+			Value:     &plugin.headers,
+		}, {
+			Path:      "state-file",
+			Env:       "NGINX_CHECK_STATE_FILE",
+			Argument:  "state-file",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "Path to a file used to persist counters between runs, to compute rate metrics and --min-requests-rate",
+			Value:     &plugin.stateFile,
+		}, {
+			Path:      "max-active",
+			Env:       "NGINX_CHECK_MAX_ACTIVE",
+			Argument:  "max-active",
+			Shorthand: "",
+			Default:   float64(0),
+			Usage:     "Critical threshold for active connections (0 to disable)",
+			Value:     &plugin.maxActive,
+		}, {
+			Path:      "min-requests-rate",
+			Env:       "NGINX_CHECK_MIN_REQUESTS_RATE",
+			Argument:  "min-requests-rate",
+			Shorthand: "",
+			Default:   float64(0),
+			Usage:     "Warning threshold for requests handled per second, requires --state-file (0 to disable)",
+			Value:     &plugin.minRequestsRate,
+		}, {
+			Path:      "max-waiting-ratio",
+			Env:       "NGINX_CHECK_MAX_WAITING_RATIO",
+			Argument:  "max-waiting-ratio",
+			Shorthand: "",
+			Default:   float64(0),
+			Usage:     "Warning threshold for the ratio of waiting to active connections (0 to disable)",
+			Value:     &plugin.maxWaitingRatio,
 		},
 	}
 )
@@ -103,40 +280,235 @@ func main() {
 }
 
 func checkArgs(_ *types.Event) (int, error) {
-	if plugin.url != "" {
-		nginxUrl = strings.TrimSpace(plugin.url)
-		parsedUrl, err := url.Parse(nginxUrl)
+	if plugin.alias != "" {
+		log.SetPrefix(fmt.Sprintf("[%s] ", plugin.alias))
+	}
+
+	if plugin.mode != nginx.ModeStub && plugin.mode != nginx.ModePlus {
+		return sensu.CheckStateCritical, fmt.Errorf("invalid mode %q: must be %q or %q", plugin.mode, nginx.ModeStub, nginx.ModePlus)
+	}
+
+	targets, err := buildTargets()
+	if err != nil {
+		return sensu.CheckStateCritical, err
+	}
+	nginxTargets = targets
+
+	if plugin.basicAuthPasswordFile != "" {
+		password, err := ioutil.ReadFile(plugin.basicAuthPasswordFile)
 		if err != nil {
-			return sensu.CheckStateCritical, fmt.Errorf("invalid url provided: %s", err.Error())
+			return sensu.CheckStateCritical, fmt.Errorf("error reading basic-auth-password-file: %s", err.Error())
 		}
-		nginxHostname = parsedUrl.Hostname()
-		nginxPort = parsedUrl.Port()
-	} else {
-		nginxUrl = fmt.Sprintf("http://%s:%d/%s", plugin.hostname, plugin.port, strings.TrimLeft(plugin.statusPath, "/"))
-		_, err := url.Parse(nginxUrl)
+		plugin.basicAuthPassword = strings.TrimSpace(string(password))
+	}
+
+	headers, err := parseHeaders(plugin.headers)
+	if err != nil {
+		return sensu.CheckStateCritical, err
+	}
+	nginxHeaders = headers
+
+	nginxClient, err = nginx.NewHTTPClient(nginx.ClientConfig{
+		CACertFile:         plugin.caCert,
+		ClientCertFile:     plugin.clientCert,
+		ClientKeyFile:      plugin.clientKey,
+		InsecureSkipVerify: plugin.insecureSkipVerify,
+		Timeout:            time.Duration(plugin.timeout) * time.Second,
+	})
+	if err != nil {
+		return sensu.CheckStateCritical, fmt.Errorf("error configuring http client: %s", err.Error())
+	}
+
+	return sensu.CheckStateOK, nil
+}
+
+// parseHeaders turns a list of "key=value" entries, as given via --header, into a map.
+func parseHeaders(entries []string) (map[string]string, error) {
+	headers := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid header %q: expected key=value", entry)
+		}
+		headers[parts[0]] = parts[1]
+	}
+	return headers, nil
+}
+
+// buildTargets resolves the configured targets to scrape, in order of precedence:
+// --targets-file, then --target/--url, then the single target built from
+// hostname/port/status-path.
+func buildTargets() ([]nginx.Target, error) {
+	if plugin.targetsFile != "" {
+		return loadTargetsFile(plugin.targetsFile)
+	}
+
+	var targets []nginx.Target
+	for _, rawURL := range plugin.urls {
+		target, err := targetFromURL(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	for _, spec := range plugin.targets {
+		target, err := targetFromSpec(spec)
 		if err != nil {
-			return sensu.CheckStateCritical, fmt.Errorf("invalid url built from hostname, port and status-path: %s", nginxUrl)
+			return nil, err
 		}
-		nginxHostname = plugin.hostname
-		nginxPort = strconv.FormatUint(uint64(plugin.port), 10)
+		targets = append(targets, target)
+	}
+	if len(targets) > 0 {
+		return targets, nil
 	}
 
-	return sensu.CheckStateOK, nil
+	target := nginx.Target{
+		URL:      fmt.Sprintf("http://%s:%d/%s", plugin.hostname, plugin.port, strings.TrimLeft(plugin.statusPath, "/")),
+		Hostname: plugin.hostname,
+		Port:     strconv.FormatUint(uint64(plugin.port), 10),
+	}
+	if _, err := url.Parse(target.URL); err != nil {
+		return nil, fmt.Errorf("invalid url built from hostname, port and status-path: %s", target.URL)
+	}
+	return []nginx.Target{target}, nil
 }
 
-func executeCheck(_ *types.Event) (int, error) {
-	metrics, err := nginx.GetMetrics(nginxUrl, nginxHostname, nginxPort, time.Duration(plugin.timeout)*time.Second)
+// targetFromURL builds a Target from a full status URL, e.g. from --url.
+func targetFromURL(rawURL string) (nginx.Target, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
-		fmt.Printf("error generating nginx metrics: %s", err.Error())
-		return sensu.CheckStateCritical, nil
+		return nginx.Target{}, fmt.Errorf("invalid url provided: %s", err.Error())
+	}
+	return nginx.Target{URL: rawURL, Hostname: parsedURL.Hostname(), Port: parsedURL.Port()}, nil
+}
+
+// targetFromSpec builds a Target from a "host:port:path" --target entry. The path defaults to
+// the configured --status-path when omitted.
+func targetFromSpec(spec string) (nginx.Target, error) {
+	parts := strings.SplitN(spec, ":", 3)
+	if len(parts) < 2 {
+		return nginx.Target{}, fmt.Errorf("invalid target %q: expected host:port[:path]", spec)
+	}
+
+	hostname, port, path := parts[0], parts[1], plugin.statusPath
+	if len(parts) == 3 {
+		path = parts[2]
+	}
+
+	return nginx.Target{
+		URL:      fmt.Sprintf("http://%s:%s/%s", hostname, port, strings.TrimLeft(path, "/")),
+		Hostname: hostname,
+		Port:     port,
+	}, nil
+}
+
+// loadTargetsFile reads a YAML or JSON file (by extension) listing targets to scrape.
+func loadTargetsFile(path string) ([]nginx.Target, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading targets file: %s", err.Error())
+	}
+
+	var specs []targetSpec
+	if strings.HasSuffix(path, ".json") {
+		err = json.Unmarshal(data, &specs)
+	} else {
+		err = yaml.Unmarshal(data, &specs)
 	}
-	err = printMetrics(metrics)
 	if err != nil {
-		fmt.Printf("error printing metrics: %s", err.Error())
+		return nil, fmt.Errorf("error decoding targets file: %s", err.Error())
+	}
+
+	targets := make([]nginx.Target, 0, len(specs))
+	for _, spec := range specs {
+		if spec.URL != "" {
+			target, err := targetFromURL(spec.URL)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, target)
+			continue
+		}
+
+		path := spec.Path
+		if path == "" {
+			path = "nginx_status"
+		}
+		targets = append(targets, nginx.Target{
+			URL:      fmt.Sprintf("http://%s:%d/%s", spec.Hostname, spec.Port, strings.TrimLeft(path, "/")),
+			Hostname: spec.Hostname,
+			Port:     strconv.FormatUint(uint64(spec.Port), 10),
+		})
+	}
+
+	return targets, nil
+}
+
+func executeCheck(_ *types.Event) (int, error) {
+	opts := nginx.ScrapeOptions{
+		Mode:              plugin.mode,
+		Format:            plugin.format,
+		Alias:             plugin.alias,
+		Client:            nginxClient,
+		BasicAuthUser:     plugin.basicAuthUser,
+		BasicAuthPassword: plugin.basicAuthPassword,
+		Headers:           nginxHeaders,
+	}
+
+	metrics := nginx.GetMetricsForTargets(nginxTargets, opts, int(plugin.concurrency))
+
+	var previousState *nginx.StateSnapshot
+	if plugin.stateFile != "" {
+		state, err := nginx.LoadState(plugin.stateFile)
+		if err != nil {
+			log.Printf("error loading state file: %s", err.Error())
+		} else {
+			previousState = state
+		}
+	}
+
+	var currentState *nginx.StateSnapshot
+	metrics, currentState = nginx.AddDerivedMetrics(metrics, plugin.alias, previousState, time.Now().UnixMilli())
+
+	if plugin.stateFile != "" {
+		if err := nginx.SaveState(plugin.stateFile, currentState); err != nil {
+			log.Printf("error writing state file: %s", err.Error())
+		}
+	}
+
+	if err := printMetrics(metrics); err != nil {
+		log.Printf("error printing metrics: %s", err.Error())
 		return sensu.CheckStateCritical, nil
 	}
 
-	return sensu.CheckStateOK, nil
+	state := sensu.CheckStateOK
+	for _, violation := range nginx.EvaluateThresholds(metrics, buildThresholds()) {
+		log.Printf("%s:%s: %s", violation.Hostname, violation.Port, violation.Message)
+		if violation.Severity == nginx.SeverityCritical {
+			state = sensu.CheckStateCritical
+		} else if state != sensu.CheckStateCritical {
+			state = sensu.CheckStateWarning
+		}
+	}
+
+	return state, nil
+}
+
+// buildThresholds turns the configured --max-active/--min-requests-rate/--max-waiting-ratio flags
+// into a nginx.Thresholds, treating 0 (the default) as "disabled", consistent with --timeout.
+func buildThresholds() nginx.Thresholds {
+	var thresholds nginx.Thresholds
+	if plugin.maxActive != 0 {
+		thresholds.MaxActive = &plugin.maxActive
+	}
+	if plugin.minRequestsRate != 0 {
+		thresholds.MinRequestsRate = &plugin.minRequestsRate
+	}
+	if plugin.maxWaitingRatio != 0 {
+		thresholds.MaxWaitingRatio = &plugin.maxWaitingRatio
+	}
+	return thresholds
 }
 
 func printMetrics(metrics []*dto.MetricFamily) error {
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetFromSpec(t *testing.T) {
+	plugin.statusPath = "nginx_status"
+
+	t.Run("host and port", func(t *testing.T) {
+		target, err := targetFromSpec("nginx.local:81")
+		require.NoError(t, err)
+		assert.Equal(t, "http://nginx.local:81/nginx_status", target.URL)
+		assert.Equal(t, "nginx.local", target.Hostname)
+		assert.Equal(t, "81", target.Port)
+	})
+
+	t.Run("host, port and path", func(t *testing.T) {
+		target, err := targetFromSpec("nginx.local:81:status")
+		require.NoError(t, err)
+		assert.Equal(t, "http://nginx.local:81/status", target.URL)
+	})
+
+	t.Run("missing port", func(t *testing.T) {
+		_, err := targetFromSpec("nginx.local")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "expected host:port[:path]")
+	})
+}
+
+func TestParseHeaders(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		headers, err := parseHeaders([]string{"X-Test=value", "X-Other=a=b"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"X-Test": "value", "X-Other": "a=b"}, headers)
+	})
+
+	t.Run("empty list", func(t *testing.T) {
+		headers, err := parseHeaders(nil)
+		require.NoError(t, err)
+		assert.Empty(t, headers)
+	})
+
+	t.Run("malformed entry", func(t *testing.T) {
+		headers, err := parseHeaders([]string{"no-equals-sign"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "expected key=value")
+		assert.Nil(t, headers)
+	})
+}
+
+func TestLoadTargetsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nginx-check-targets")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	t.Run("json", func(t *testing.T) {
+		path := filepath.Join(dir, "targets.json")
+		content := `[{"hostname": "one.local", "port": 81}, {"url": "http://two.local:82/status"}]`
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0o600))
+
+		targets, err := loadTargetsFile(path)
+		require.NoError(t, err)
+		require.Len(t, targets, 2)
+		assert.Equal(t, "http://one.local:81/nginx_status", targets[0].URL)
+		assert.Equal(t, "http://two.local:82/status", targets[1].URL)
+		assert.Equal(t, "two.local", targets[1].Hostname)
+		assert.Equal(t, "82", targets[1].Port)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		path := filepath.Join(dir, "targets.yaml")
+		content := "- hostname: one.local\n  port: 81\n  path: custom_status\n"
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0o600))
+
+		targets, err := loadTargetsFile(path)
+		require.NoError(t, err)
+		require.Len(t, targets, 1)
+		assert.Equal(t, "http://one.local:81/custom_status", targets[0].URL)
+	})
+
+	t.Run("malformed content", func(t *testing.T) {
+		path := filepath.Join(dir, "bad.json")
+		require.NoError(t, ioutil.WriteFile(path, []byte("not json"), 0o600))
+
+		targets, err := loadTargetsFile(path)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "error decoding targets file")
+		assert.Nil(t, targets)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		targets, err := loadTargetsFile(filepath.Join(dir, "missing.json"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "error reading targets file")
+		assert.Nil(t, targets)
+	})
+}
+
+func TestBuildTargets(t *testing.T) {
+	resetPlugin := func() {
+		plugin.targetsFile = ""
+		plugin.urls = nil
+		plugin.targets = nil
+		plugin.hostname = "localhost"
+		plugin.port = 81
+		plugin.statusPath = "nginx_status"
+	}
+
+	t.Run("targets-file takes precedence", func(t *testing.T) {
+		resetPlugin()
+		dir, err := ioutil.TempDir("", "nginx-check-targets")
+		require.NoError(t, err)
+		defer func() { _ = os.RemoveAll(dir) }()
+
+		path := filepath.Join(dir, "targets.json")
+		require.NoError(t, ioutil.WriteFile(path, []byte(`[{"hostname": "from-file.local", "port": 81}]`), 0o600))
+
+		plugin.targetsFile = path
+		plugin.urls = []string{"http://from-url.local:81/nginx_status"}
+		plugin.targets = []string{"from-target.local:81"}
+
+		targets, err := buildTargets()
+		require.NoError(t, err)
+		require.Len(t, targets, 1)
+		assert.Equal(t, "from-file.local", targets[0].Hostname)
+	})
+
+	t.Run("url and target entries are combined when no targets-file", func(t *testing.T) {
+		resetPlugin()
+		plugin.urls = []string{"http://from-url.local:81/nginx_status"}
+		plugin.targets = []string{"from-target.local:82"}
+
+		targets, err := buildTargets()
+		require.NoError(t, err)
+		require.Len(t, targets, 2)
+		assert.Equal(t, "from-url.local", targets[0].Hostname)
+		assert.Equal(t, "from-target.local", targets[1].Hostname)
+	})
+
+	t.Run("falls back to hostname/port/status-path", func(t *testing.T) {
+		resetPlugin()
+
+		targets, err := buildTargets()
+		require.NoError(t, err)
+		require.Len(t, targets, 1)
+		assert.Equal(t, "http://localhost:81/nginx_status", targets[0].URL)
+		assert.Equal(t, "localhost", targets[0].Hostname)
+		assert.Equal(t, "81", targets[0].Port)
+	})
+}
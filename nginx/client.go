@@ -0,0 +1,51 @@
+package nginx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// ClientConfig configures the HTTP client used to scrape NGINX status endpoints: TLS trust and
+// client certificates, and the per-request timeout. Basic auth credentials and extra headers are
+// request-level concerns and are carried on ScrapeOptions instead.
+type ClientConfig struct {
+	CACertFile         string
+	ClientCertFile     string
+	ClientKeyFile      string
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+}
+
+// NewHTTPClient builds a *http.Client configured per cfg, with a single *http.Transport meant to
+// be reused across every target scraped by a check invocation.
+func NewHTTPClient(cfg ClientConfig) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	if cfg.CACertFile != "" {
+		caCert, err := ioutil.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ca-cert: %s", err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no valid certificates found in ca-cert %s", cfg.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCertFile != "" || cfg.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client cert/key: %s", err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := &http.Transport{TLSClientConfig: tlsConfig}
+
+	return &http.Client{Transport: transport, Timeout: cfg.Timeout}, nil
+}
@@ -0,0 +1,46 @@
+package nginx
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	t.Run("default config", func(t *testing.T) {
+		client, err := NewHTTPClient(ClientConfig{Timeout: 5 * time.Second})
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Second, client.Timeout)
+		assert.IsType(t, &http.Transport{}, client.Transport)
+	})
+
+	t.Run("invalid ca-cert path", func(t *testing.T) {
+		client, err := NewHTTPClient(ClientConfig{CACertFile: "/no/such/file"})
+		require.Error(t, err)
+		require.Nil(t, client)
+	})
+
+	t.Run("invalid ca-cert content", func(t *testing.T) {
+		file, err := ioutil.TempFile("", "ca-cert-*.pem")
+		require.NoError(t, err)
+		defer func() { _ = os.Remove(file.Name()) }()
+		_, err = file.WriteString("not a certificate")
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		client, err := NewHTTPClient(ClientConfig{CACertFile: file.Name()})
+		require.Error(t, err)
+		require.Nil(t, client)
+	})
+
+	t.Run("invalid client cert/key", func(t *testing.T) {
+		client, err := NewHTTPClient(ClientConfig{ClientCertFile: "/no/such/cert", ClientKeyFile: "/no/such/key"})
+		require.Error(t, err)
+		require.Nil(t, client)
+	})
+}
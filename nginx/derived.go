@@ -0,0 +1,147 @@
+package nginx
+
+import (
+	dto "github.com/prometheus/client_model/go"
+)
+
+var derivedMetricHelp = map[string]*MetricDesc{
+	"nginx_dropped":                 {"connections dropped (accepted minus handled)", dto.MetricType_GAUGE},
+	"nginx_requests_per_connection": {"average requests handled per connection", dto.MetricType_GAUGE},
+	"nginx_accepts_rate":            {"accepted connections per second since the last scrape", dto.MetricType_GAUGE},
+	"nginx_handled_rate":            {"handled connections per second since the last scrape", dto.MetricType_GAUGE},
+	"nginx_requests_rate":           {"handled requests per second since the last scrape", dto.MetricType_GAUGE},
+}
+
+// rateSourceCounters lists the counters a rate gauge is derived from; a rate is only emitted for
+// a target once a previous snapshot for it is available.
+var rateSourceCounters = []string{"nginx_accepts", "nginx_handled", "nginx_requests"}
+
+// AddDerivedMetrics computes nginx_dropped and nginx_requests_per_connection from the
+// nginx_accepts/nginx_handled/nginx_requests counters already present in metrics, for every
+// target found in them. When previous holds a counter snapshot for a target (loaded from a
+// --state-file via LoadState), it also emits nginx_accepts_rate/nginx_handled_rate/
+// nginx_requests_rate gauges computed against it. It returns the extended metrics plus the
+// current counter snapshot, for the caller to persist via SaveState.
+func AddDerivedMetrics(metrics []*dto.MetricFamily, alias string, previous *StateSnapshot, nowMS int64) ([]*dto.MetricFamily, *StateSnapshot) {
+	current := &StateSnapshot{Targets: map[string]TargetState{}}
+
+	for _, target := range targetsIn(metrics) {
+		values := counterValues(metrics, target.hostname, target.port)
+		current.Targets[targetKey(target.hostname, target.port)] = TargetState{TimestampMS: nowMS, Counters: values}
+
+		accepts, hasAccepts := values["nginx_accepts"]
+		handled, hasHandled := values["nginx_handled"]
+		requests, hasRequests := values["nginx_requests"]
+
+		if hasAccepts && hasHandled {
+			metrics = append(metrics, buildDerivedGauge("nginx_dropped", accepts-handled, nowMS, target.hostname, target.port, alias))
+		}
+		if hasHandled && hasRequests && handled > 0 {
+			metrics = append(metrics, buildDerivedGauge("nginx_requests_per_connection", requests/handled, nowMS, target.hostname, target.port, alias))
+		}
+
+		if previous == nil {
+			continue
+		}
+		previousState, ok := previous.Targets[targetKey(target.hostname, target.port)]
+		if !ok {
+			continue
+		}
+		elapsedSeconds := float64(nowMS-previousState.TimestampMS) / 1000
+		if elapsedSeconds <= 0 {
+			continue
+		}
+		for _, name := range rateSourceCounters {
+			value, hasValue := values[name]
+			previousValue, hasPrevious := previousState.Counters[name]
+			if !hasValue || !hasPrevious || value < previousValue {
+				continue
+			}
+			metrics = append(metrics, buildDerivedGauge(name+"_rate", (value-previousValue)/elapsedSeconds, nowMS, target.hostname, target.port, alias))
+		}
+	}
+
+	return metrics, current
+}
+
+func buildDerivedGauge(name string, value float64, timestampMS int64, hostname string, port string, alias string) *dto.MetricFamily {
+	desc := derivedMetricHelp[name]
+	metricName := name
+
+	labels := []*dto.LabelPair{{Name: &labelHost, Value: &hostname}, {Name: &labelPort, Value: &port}}
+	if alias != "" {
+		labels = append(labels, &dto.LabelPair{Name: &labelAlias, Value: &alias})
+	}
+
+	metric := &dto.Metric{
+		Label:       labels,
+		Gauge:       &dto.Gauge{Value: &value},
+		TimestampMs: &timestampMS,
+	}
+
+	return &dto.MetricFamily{Name: &metricName, Help: &desc.help, Type: &desc.metricType, Metric: []*dto.Metric{metric}}
+}
+
+// metricTarget identifies a target by the host/port labels found on its metrics.
+type metricTarget struct {
+	hostname string
+	port     string
+}
+
+// targetsIn returns the distinct (hostname, port) pairs labelled on metrics.
+func targetsIn(metrics []*dto.MetricFamily) []metricTarget {
+	seen := make(map[metricTarget]bool)
+	var targets []metricTarget
+
+	for _, family := range metrics {
+		for _, metric := range family.Metric {
+			var target metricTarget
+			for _, label := range metric.Label {
+				switch label.GetName() {
+				case labelHost:
+					target.hostname = label.GetValue()
+				case labelPort:
+					target.port = label.GetValue()
+				}
+			}
+			if target.hostname == "" && target.port == "" {
+				continue
+			}
+			if !seen[target] {
+				seen[target] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	return targets
+}
+
+// counterValues returns the value of every counter/gauge metric labelled for hostname/port,
+// keyed by metric family name.
+func counterValues(metrics []*dto.MetricFamily, hostname string, port string) map[string]float64 {
+	values := make(map[string]float64)
+	for _, family := range metrics {
+		for _, metric := range family.Metric {
+			if !hasLabel(metric, labelHost, hostname) || !hasLabel(metric, labelPort, port) {
+				continue
+			}
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				values[family.GetName()] = metric.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				values[family.GetName()] = metric.GetGauge().GetValue()
+			}
+		}
+	}
+	return values
+}
+
+func hasLabel(metric *dto.Metric, name string, value string) bool {
+	for _, label := range metric.Label {
+		if label.GetName() == name {
+			return label.GetValue() == value
+		}
+	}
+	return false
+}
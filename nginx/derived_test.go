@@ -0,0 +1,69 @@
+package nginx
+
+import (
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddDerivedMetrics_NoPreviousState(t *testing.T) {
+	now := time.Now()
+	nowFn = func() time.Time { return now }
+
+	metrics, err := extractMetrics([]byte(allLinesOk), hostname, port)
+	require.NoError(t, err)
+
+	extended, current := AddDerivedMetrics(metrics, "", nil, now.UnixMilli())
+
+	byName := metricsByName(extended)
+	require.Contains(t, byName, "nginx_dropped")
+	assert.Equal(t, acceptsValue-handledValue, byName["nginx_dropped"].Metric[0].GetGauge().GetValue())
+
+	require.Contains(t, byName, "nginx_requests_per_connection")
+	assert.Equal(t, requestsValue/handledValue, byName["nginx_requests_per_connection"].Metric[0].GetGauge().GetValue())
+
+	assert.NotContains(t, byName, "nginx_accepts_rate")
+
+	require.Contains(t, current.Targets, targetKey(hostname, port))
+	assert.Equal(t, acceptsValue, current.Targets[targetKey(hostname, port)].Counters["nginx_accepts"])
+}
+
+func TestAddDerivedMetrics_WithPreviousState(t *testing.T) {
+	now := time.Now()
+	nowFn = func() time.Time { return now }
+
+	metrics, err := extractMetrics([]byte(allLinesOk), hostname, port)
+	require.NoError(t, err)
+
+	previous := &StateSnapshot{
+		Targets: map[string]TargetState{
+			targetKey(hostname, port): {
+				TimestampMS: now.Add(-10 * time.Second).UnixMilli(),
+				Counters:    map[string]float64{"nginx_accepts": acceptsValue - 20, "nginx_handled": handledValue - 10, "nginx_requests": requestsValue - 50},
+			},
+		},
+	}
+
+	extended, _ := AddDerivedMetrics(metrics, "blue", previous, now.UnixMilli())
+
+	byName := metricsByName(extended)
+	require.Contains(t, byName, "nginx_accepts_rate")
+	assert.Equal(t, float64(2), byName["nginx_accepts_rate"].Metric[0].GetGauge().GetValue())
+
+	labels := make(map[string]string)
+	for _, label := range byName["nginx_accepts_rate"].Metric[0].Label {
+		labels[label.GetName()] = label.GetValue()
+	}
+	assert.Equal(t, "blue", labels[labelAlias])
+}
+
+func metricsByName(metrics []*dto.MetricFamily) map[string]*dto.MetricFamily {
+	byName := make(map[string]*dto.MetricFamily, len(metrics))
+	for _, family := range metrics {
+		byName[family.GetName()] = family
+	}
+	return byName
+}
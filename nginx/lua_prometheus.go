@@ -0,0 +1,30 @@
+package nginx
+
+import (
+	"bytes"
+	"fmt"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// relabelLuaPrometheusMetrics parses a body already in Prometheus text exposition format, as
+// produced by the openresty/nginx-lua-prometheus module, and adds host/port labels to every
+// metric so it can be told apart from other scrape targets.
+func relabelLuaPrometheusMetrics(content []byte, hostname string, port string) ([]*dto.MetricFamily, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing lua-prometheus status payload: %s", err.Error())
+	}
+
+	metrics := make([]*dto.MetricFamily, 0, len(families))
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			metric.Label = append(metric.Label, &dto.LabelPair{Name: &labelHost, Value: &hostname}, &dto.LabelPair{Name: &labelPort, Value: &port})
+		}
+		metrics = append(metrics, family)
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,37 @@
+package nginx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const luaPrometheusOk = `# HELP nginx_http_requests_total Number of HTTP requests
+# TYPE nginx_http_requests_total counter
+nginx_http_requests_total{status="200"} 10
+`
+
+func TestRelabelLuaPrometheusMetrics(t *testing.T) {
+	metrics, err := relabelLuaPrometheusMetrics([]byte(luaPrometheusOk), hostname, port)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	family := metrics[0]
+	assert.Equal(t, "nginx_http_requests_total", family.GetName())
+	require.Len(t, family.Metric, 1)
+
+	labels := make(map[string]string, len(family.Metric[0].Label))
+	for _, label := range family.Metric[0].Label {
+		labels[label.GetName()] = label.GetValue()
+	}
+	assert.Equal(t, "200", labels["status"])
+	assert.Equal(t, hostname, labels[labelHost])
+	assert.Equal(t, port, labels[labelPort])
+}
+
+func TestRelabelLuaPrometheusMetrics_InvalidInput(t *testing.T) {
+	metrics, err := relabelLuaPrometheusMetrics([]byte("not prometheus text"), hostname, port)
+	require.Error(t, err)
+	require.Nil(t, metrics)
+}
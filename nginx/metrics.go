@@ -20,8 +20,9 @@ type MetricDesc struct {
 }
 
 var (
-	labelHost = "host"
-	labelPort = "port"
+	labelHost  = "host"
+	labelPort  = "port"
+	labelAlias = "alias"
 
 	line1Regex = regexp.MustCompile("^Active connections: (\\d+)$")
 	line3Regex = regexp.MustCompile("^(\\d+)\\s+(\\d+)\\s+(\\d+)$")
@@ -40,35 +41,106 @@ var (
 	nowFn = time.Now
 )
 
-// GetMetrics loads the data from the NGINX status URL and generate Prometheus metrics from
-// the content.
-func GetMetrics(url string, hostname string, port string, timeout time.Duration) ([]*dto.MetricFamily, error) {
-	data, err := loadData(url, timeout)
+// ModeStub selects the plain-text stub_status format.
+const ModeStub = "stub"
+
+// ModePlus selects the NGINX Plus JSON status API.
+const ModePlus = "plus"
+
+// ScrapeOptions bundles everything needed to scrape a target and turn its response into
+// Prometheus metrics: which status source and format to use, the (shared, already configured)
+// HTTP client to scrape it with, and optional request auth/headers and metric labelling.
+type ScrapeOptions struct {
+	Mode   string
+	Format string
+	Alias  string
+
+	Client            *http.Client
+	BasicAuthUser     string
+	BasicAuthPassword string
+	Headers           map[string]string
+}
+
+// GetMetrics loads the data from the NGINX status URL and generates Prometheus metrics from
+// the content, per opts. opts.Mode selects which status source to scrape: ModeStub for a single
+// status endpoint (stub_status, VTS or lua-prometheus, see opts.Format), or ModePlus for the
+// richer NGINX Plus status API. opts.Format selects the StatusParser to use for ModeStub; pass
+// FormatAuto to sniff it from the response. When opts.Alias is non-empty, it is added as an extra
+// "alias" label on every returned metric so several instances can be told apart.
+func GetMetrics(url string, hostname string, port string, opts ScrapeOptions) ([]*dto.MetricFamily, error) {
+	metrics, err := scrapeMetrics(url, hostname, port, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return extractMetrics(data, hostname, port)
+	if opts.Alias != "" {
+		addAliasLabel(metrics, opts.Alias)
+	}
+
+	return metrics, nil
 }
 
-func loadData(url string, timeout time.Duration) ([]byte, error) {
-	client := &http.Client{Timeout: timeout}
-	resp, err := client.Get(url)
+func scrapeMetrics(url string, hostname string, port string, opts ScrapeOptions) ([]*dto.MetricFamily, error) {
+	if opts.Mode == ModePlus {
+		return loadPlusMetrics(url, hostname, port, opts)
+	}
+
+	body, contentType, err := loadData(url, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	parser, err := resolveParser(opts.Format, contentType, body)
 	if err != nil {
 		return nil, err
 	}
+
+	return parser.Parse(body, hostname, port)
+}
+
+// addAliasLabel adds an "alias" label to every metric in every family, in place.
+func addAliasLabel(metrics []*dto.MetricFamily, alias string) {
+	for _, family := range metrics {
+		for _, metric := range family.Metric {
+			metric.Label = append(metric.Label, &dto.LabelPair{Name: &labelAlias, Value: &alias})
+		}
+	}
+}
+
+func loadData(url string, opts ScrapeOptions) ([]byte, string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if opts.BasicAuthUser != "" {
+		req.SetBasicAuth(opts.BasicAuthUser, opts.BasicAuthPassword)
+	}
+	for name, value := range opts.Headers {
+		req.Header.Set(name, value)
+	}
+
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("invalid nginx status code: %d", resp.StatusCode)
+		return nil, "", fmt.Errorf("invalid nginx status code: %d", resp.StatusCode)
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("error reading body content: %s", err.Error())
+		return nil, "", fmt.Errorf("error reading body content: %s", err.Error())
 	}
 
-	return body, nil
+	return body, resp.Header.Get("Content-Type"), nil
 }
 
 // extractMetrics parses the metrics from the nginx status page output and returns
@@ -158,6 +230,50 @@ func extractMetrics(content []byte, hostname string, port string) ([]*dto.Metric
 	return metrics, nil
 }
 
+// findMetricFamily returns the family with the given name, so callers reporting the same metric
+// for several zones/peers/targets can append to one family instead of creating a duplicate.
+func findMetricFamily(metrics []*dto.MetricFamily, name string) *dto.MetricFamily {
+	for _, family := range metrics {
+		if family.GetName() == name {
+			return family
+		}
+	}
+	return nil
+}
+
+// addMetric appends a metric labelled with host/port (plus any extra "name, value" label pairs)
+// to the family for the given metric name, creating that family on first use. Status payloads
+// typically report a metric once per zone/peer/resolver, so an existing family for the name is
+// reused rather than creating a second family with a duplicate name.
+func addMetric(metrics []*dto.MetricFamily, name string, help string, metricType dto.MetricType, value float64, timestampMS int64, hostname string, port string, extraLabels ...string) []*dto.MetricFamily {
+	labels := []*dto.LabelPair{{Name: &labelHost, Value: &hostname}, {Name: &labelPort, Value: &port}}
+	for i := 0; i+1 < len(extraLabels); i += 2 {
+		labelName, labelValue := extraLabels[i], extraLabels[i+1]
+		labels = append(labels, &dto.LabelPair{Name: &labelName, Value: &labelValue})
+	}
+
+	metric := &dto.Metric{Label: labels, TimestampMs: &timestampMS}
+	if metricType == dto.MetricType_COUNTER {
+		metric.Counter = &dto.Counter{Value: &value}
+	} else {
+		metric.Gauge = &dto.Gauge{Value: &value}
+	}
+
+	if family := findMetricFamily(metrics, name); family != nil {
+		family.Metric = append(family.Metric, metric)
+		return metrics
+	}
+
+	familyName := name
+	family := &dto.MetricFamily{
+		Name:   &familyName,
+		Help:   &help,
+		Type:   &metricType,
+		Metric: []*dto.Metric{metric},
+	}
+	return append(metrics, family)
+}
+
 func addNewMetric(metrics []*dto.MetricFamily, metricType string, value uint64, timestampMS int64, hostname string, port string) []*dto.MetricFamily {
 	metricDesc := metricHelp[metricType]
 	if metricDesc == nil {
@@ -51,32 +51,44 @@ func TestLoadData(t *testing.T) {
 	defer testServer.Close()
 
 	t.Run("happy scenario", func(t *testing.T) {
-		data, err := loadData(testServer.URL+"/nginx_status", 10*time.Second)
+		data, contentType, err := loadData(testServer.URL+"/nginx_status", scrapeOptsWithTimeout(10*time.Second))
 		require.NoError(t, err)
 		assert.Equal(t, allLinesOk, string(data))
+		assert.Equal(t, "text/plain", contentType)
 	})
 
 	t.Run("invalid url (404 error)", func(t *testing.T) {
-		data, err := loadData(testServer.URL+"/seriously", 10*time.Second)
+		data, _, err := loadData(testServer.URL+"/seriously", scrapeOptsWithTimeout(10*time.Second))
 		require.Error(t, err)
 		require.Nil(t, data)
 	})
 
 	t.Run("invalid host/port", func(t *testing.T) {
-		data, err := loadData("http://127.0.0.1:33333", 10*time.Second)
+		data, _, err := loadData("http://127.0.0.1:33333", scrapeOptsWithTimeout(10*time.Second))
 		require.Error(t, err)
 		require.Nil(t, data)
 	})
 
 	t.Run("request timeout", func(t *testing.T) {
 		start := time.Now()
-		data, err := loadData(testServer.URL+"/sleep", 1*time.Second)
+		data, _, err := loadData(testServer.URL+"/sleep", scrapeOptsWithTimeout(1*time.Second))
 		timeDelta := time.Since(start)
 		// the /sleep url waits 10 seconds before returning. anything bellow 8 seconds is fine
 		assert.Error(t, err)
 		assert.Nil(t, data)
 		assert.Less(t, int64(timeDelta), int64(8*time.Second))
 	})
+
+	t.Run("basic auth and custom headers", func(t *testing.T) {
+		opts := scrapeOptsWithTimeout(10 * time.Second)
+		opts.BasicAuthUser = "user"
+		opts.BasicAuthPassword = "pass"
+		opts.Headers = map[string]string{"X-Test": "value"}
+
+		data, _, err := loadData(testServer.URL+"/authed", opts)
+		require.NoError(t, err)
+		assert.Equal(t, allLinesOk, string(data))
+	})
 }
 
 func TestExtractMetrics(t *testing.T) {
@@ -168,9 +180,47 @@ func TestExtractMetrics(t *testing.T) {
 	}
 }
 
+func TestGetMetrics_Alias(t *testing.T) {
+	testServer := createTestServer()
+	defer testServer.Close()
+
+	opts := scrapeOptsWithTimeout(10 * time.Second)
+	opts.Mode, opts.Format, opts.Alias = ModeStub, FormatStub, "blue"
+
+	metrics, err := GetMetrics(testServer.URL+"/nginx_status", hostname, port, opts)
+	require.NoError(t, err)
+	require.NotEmpty(t, metrics)
+
+	for _, family := range metrics {
+		require.Len(t, family.Metric, 1)
+		labels := make(map[string]string, len(family.Metric[0].Label))
+		for _, label := range family.Metric[0].Label {
+			labels[label.GetName()] = label.GetValue()
+		}
+		assert.Equal(t, "blue", labels[labelAlias])
+	}
+}
+
+// scrapeOptsWithTimeout returns a ScrapeOptions with a plain *http.Client using the given
+// timeout, as a shorthand for tests that don't care about TLS/auth/headers.
+func scrapeOptsWithTimeout(timeout time.Duration) ScrapeOptions {
+	return ScrapeOptions{Client: &http.Client{Timeout: timeout}}
+}
+
 func createTestServer() *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.HasSuffix(r.RequestURI, "nginx_status") {
+		if strings.HasSuffix(r.RequestURI, "authed") {
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != "user" || pass != "pass" || r.Header.Get("X-Test") != "value" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte("UNAUTHORIZED"))
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(allLinesOk))
+		} else if strings.HasSuffix(r.RequestURI, "nginx_status") {
+			w.Header().Set("Content-Type", "text/plain")
 			w.WriteHeader(http.StatusOK)
 			_, _ = w.Write([]byte(allLinesOk))
 		} else if strings.HasSuffix(r.RequestURI, "sleep") {
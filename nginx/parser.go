@@ -0,0 +1,77 @@
+package nginx
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Supported --format values for ModeStub scrapes.
+const (
+	FormatAuto          = "auto"
+	FormatStub          = "stub"
+	FormatVTS           = "vts"
+	FormatLuaPrometheus = "lua-prometheus"
+)
+
+// StatusParser turns a raw status response body into Prometheus metric families, labelling
+// every metric with the host and port it was scraped from.
+type StatusParser interface {
+	Parse(body []byte, hostname string, port string) ([]*dto.MetricFamily, error)
+}
+
+type stubParser struct{}
+
+func (stubParser) Parse(body []byte, hostname string, port string) ([]*dto.MetricFamily, error) {
+	return extractMetrics(body, hostname, port)
+}
+
+type vtsParser struct{}
+
+func (vtsParser) Parse(body []byte, hostname string, port string) ([]*dto.MetricFamily, error) {
+	return extractVTSMetrics(body, hostname, port)
+}
+
+type luaPrometheusParser struct{}
+
+func (luaPrometheusParser) Parse(body []byte, hostname string, port string) ([]*dto.MetricFamily, error) {
+	return relabelLuaPrometheusMetrics(body, hostname, port)
+}
+
+// parsers maps a forced --format value to its StatusParser implementation.
+var parsers = map[string]StatusParser{
+	FormatStub:          stubParser{},
+	FormatVTS:           vtsParser{},
+	FormatLuaPrometheus: luaPrometheusParser{},
+}
+
+// resolveParser returns the StatusParser to use. If format is empty or FormatAuto, it sniffs the
+// response Content-Type and body prefix to pick one; otherwise it looks up the forced format.
+func resolveParser(format string, contentType string, body []byte) (StatusParser, error) {
+	if format == "" || format == FormatAuto {
+		return detectParser(contentType, body), nil
+	}
+
+	parser, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown status format: %s", format)
+	}
+	return parser, nil
+}
+
+// detectParser sniffs the response Content-Type and body prefix to decide between the
+// stub_status, nginx-vts-module and lua-prometheus formats.
+func detectParser(contentType string, body []byte) StatusParser {
+	trimmed := bytes.TrimSpace(body)
+
+	switch {
+	case strings.Contains(contentType, "application/json"), bytes.HasPrefix(trimmed, []byte("{")):
+		return vtsParser{}
+	case bytes.HasPrefix(trimmed, []byte("# HELP")), bytes.HasPrefix(trimmed, []byte("# TYPE")):
+		return luaPrometheusParser{}
+	default:
+		return stubParser{}
+	}
+}
@@ -0,0 +1,48 @@
+package nginx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectParser(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    StatusParser
+	}{
+		{"json content-type", "application/json", "{}", vtsParser{}},
+		{"json body prefix", "", "  {\"serverZones\":{}}", vtsParser{}},
+		{"lua-prometheus body prefix", "text/plain", "# HELP nginx_up\n# TYPE nginx_up gauge\n", luaPrometheusParser{}},
+		{"stub status fallback", "text/plain", "Active connections: 1\n", stubParser{}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, detectParser(test.contentType, []byte(test.body)))
+		})
+	}
+}
+
+func TestResolveParser(t *testing.T) {
+	t.Run("auto sniffs", func(t *testing.T) {
+		parser, err := resolveParser(FormatAuto, "", []byte("{}"))
+		require.NoError(t, err)
+		assert.Equal(t, vtsParser{}, parser)
+	})
+
+	t.Run("forced format", func(t *testing.T) {
+		parser, err := resolveParser(FormatStub, "application/json", []byte("{}"))
+		require.NoError(t, err)
+		assert.Equal(t, stubParser{}, parser)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		parser, err := resolveParser("bogus", "", nil)
+		require.Error(t, err)
+		require.Nil(t, parser)
+	})
+}
@@ -0,0 +1,160 @@
+package nginx
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// plusAPIVersion is the NGINX Plus API version this check queries.
+const plusAPIVersion = "8"
+
+var (
+	labelZone     = "zone"
+	labelUpstream = "upstream"
+	labelPeer     = "peer"
+
+	plusMetricHelp = map[string]*MetricDesc{
+		"nginx_plus_connections_accepted":   {"accepted client connections", dto.MetricType_COUNTER},
+		"nginx_plus_connections_dropped":    {"dropped client connections", dto.MetricType_COUNTER},
+		"nginx_plus_connections_active":     {"active client connections", dto.MetricType_GAUGE},
+		"nginx_plus_connections_idle":       {"idle client connections", dto.MetricType_GAUGE},
+		"nginx_plus_requests_total":         {"total client requests", dto.MetricType_COUNTER},
+		"nginx_plus_requests_current":       {"client requests currently being processed", dto.MetricType_GAUGE},
+		"nginx_plus_server_zone_requests":   {"requests handled by the server zone", dto.MetricType_COUNTER},
+		"nginx_plus_server_zone_responses":  {"responses sent by the server zone, by status code class", dto.MetricType_COUNTER},
+		"nginx_plus_upstream_peer_state":    {"state of the upstream peer (1 for up, 0 otherwise)", dto.MetricType_GAUGE},
+		"nginx_plus_upstream_peer_active":   {"active connections to the upstream peer", dto.MetricType_GAUGE},
+		"nginx_plus_upstream_peer_requests": {"requests sent to the upstream peer", dto.MetricType_COUNTER},
+		"nginx_plus_cache_hits":             {"cache zone hits", dto.MetricType_COUNTER},
+		"nginx_plus_cache_misses":           {"cache zone misses", dto.MetricType_COUNTER},
+		"nginx_plus_ssl_handshakes":         {"successful SSL handshakes", dto.MetricType_COUNTER},
+		"nginx_plus_ssl_handshakes_failed":  {"failed SSL handshakes", dto.MetricType_COUNTER},
+		"nginx_plus_resolver_requests":      {"name resolution requests", dto.MetricType_COUNTER},
+	}
+)
+
+// plusStatus mirrors the subset of the NGINX Plus status API response used by this check. The
+// real payload has many more fields; only those needed to produce the metrics below are kept.
+type plusStatus struct {
+	Connections struct {
+		Accepted uint64 `json:"accepted"`
+		Dropped  uint64 `json:"dropped"`
+		Active   uint64 `json:"active"`
+		Idle     uint64 `json:"idle"`
+	} `json:"connections"`
+	HTTP struct {
+		RequestsTotal struct {
+			Total   uint64 `json:"total"`
+			Current uint64 `json:"current"`
+		} `json:"requests"`
+		ServerZones map[string]struct {
+			Requests  uint64            `json:"requests"`
+			Responses map[string]uint64 `json:"responses"`
+		} `json:"server_zones"`
+		Upstreams map[string]struct {
+			Peers []struct {
+				Server   string `json:"server"`
+				State    string `json:"state"`
+				Active   uint64 `json:"active"`
+				Requests uint64 `json:"requests"`
+			} `json:"peers"`
+		} `json:"upstreams"`
+		Caches map[string]struct {
+			Hit struct {
+				Responses uint64 `json:"responses"`
+			} `json:"hit"`
+			Miss struct {
+				Responses uint64 `json:"responses"`
+			} `json:"miss"`
+		} `json:"caches"`
+	} `json:"http"`
+	SSL struct {
+		Handshakes       uint64 `json:"handshakes"`
+		HandshakesFailed uint64 `json:"handshakes_failed"`
+	} `json:"ssl"`
+	Resolvers map[string]struct {
+		Requests struct {
+			Name uint64 `json:"name"`
+		} `json:"requests"`
+	} `json:"resolvers"`
+}
+
+// plusAPIURL builds the root NGINX Plus API URL for a given status URL, e.g.
+// "http://host:81/api" becomes "http://host:81/api/8/".
+func plusAPIURL(url string) string {
+	return fmt.Sprintf("%s/%s/", strings.TrimRight(url, "/"), plusAPIVersion)
+}
+
+// loadPlusMetrics loads the aggregated status payload from the NGINX Plus API rooted at url and
+// converts it into Prometheus metrics for connections, requests, server zones, upstreams, caches,
+// SSL and resolvers.
+func loadPlusMetrics(url string, hostname string, port string, opts ScrapeOptions) ([]*dto.MetricFamily, error) {
+	body, _, err := loadData(plusAPIURL(url), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractPlusMetrics(body, hostname, port)
+}
+
+func extractPlusMetrics(content []byte, hostname string, port string) ([]*dto.MetricFamily, error) {
+	var status plusStatus
+	if err := json.Unmarshal(content, &status); err != nil {
+		return nil, fmt.Errorf("error decoding nginx plus status payload: %s", err.Error())
+	}
+
+	nowMS := nowFn().UnixMilli()
+	metrics := make([]*dto.MetricFamily, 0, 16)
+
+	metrics = addPlusMetric(metrics, "nginx_plus_connections_accepted", float64(status.Connections.Accepted), nowMS, hostname, port)
+	metrics = addPlusMetric(metrics, "nginx_plus_connections_dropped", float64(status.Connections.Dropped), nowMS, hostname, port)
+	metrics = addPlusMetric(metrics, "nginx_plus_connections_active", float64(status.Connections.Active), nowMS, hostname, port)
+	metrics = addPlusMetric(metrics, "nginx_plus_connections_idle", float64(status.Connections.Idle), nowMS, hostname, port)
+	metrics = addPlusMetric(metrics, "nginx_plus_requests_total", float64(status.HTTP.RequestsTotal.Total), nowMS, hostname, port)
+	metrics = addPlusMetric(metrics, "nginx_plus_requests_current", float64(status.HTTP.RequestsTotal.Current), nowMS, hostname, port)
+	metrics = addPlusMetric(metrics, "nginx_plus_ssl_handshakes", float64(status.SSL.Handshakes), nowMS, hostname, port)
+	metrics = addPlusMetric(metrics, "nginx_plus_ssl_handshakes_failed", float64(status.SSL.HandshakesFailed), nowMS, hostname, port)
+
+	for zone, data := range status.HTTP.ServerZones {
+		metrics = addPlusMetric(metrics, "nginx_plus_server_zone_requests", float64(data.Requests), nowMS, hostname, port, labelZone, zone)
+		for class, count := range data.Responses {
+			metrics = addPlusMetric(metrics, "nginx_plus_server_zone_responses", float64(count), nowMS, hostname, port, labelZone, zone, "code", class)
+		}
+	}
+
+	for upstream, data := range status.HTTP.Upstreams {
+		for _, peer := range data.Peers {
+			state := float64(0)
+			if peer.State == "up" {
+				state = 1
+			}
+			metrics = addPlusMetric(metrics, "nginx_plus_upstream_peer_state", state, nowMS, hostname, port, labelUpstream, upstream, labelPeer, peer.Server)
+			metrics = addPlusMetric(metrics, "nginx_plus_upstream_peer_active", float64(peer.Active), nowMS, hostname, port, labelUpstream, upstream, labelPeer, peer.Server)
+			metrics = addPlusMetric(metrics, "nginx_plus_upstream_peer_requests", float64(peer.Requests), nowMS, hostname, port, labelUpstream, upstream, labelPeer, peer.Server)
+		}
+	}
+
+	for zone, data := range status.HTTP.Caches {
+		metrics = addPlusMetric(metrics, "nginx_plus_cache_hits", float64(data.Hit.Responses), nowMS, hostname, port, labelZone, zone)
+		metrics = addPlusMetric(metrics, "nginx_plus_cache_misses", float64(data.Miss.Responses), nowMS, hostname, port, labelZone, zone)
+	}
+
+	for resolver, data := range status.Resolvers {
+		metrics = addPlusMetric(metrics, "nginx_plus_resolver_requests", float64(data.Requests.Name), nowMS, hostname, port, "resolver", resolver)
+	}
+
+	return metrics, nil
+}
+
+// addPlusMetric appends a metric for the given plus metric name, via the shared addMetric helper.
+func addPlusMetric(metrics []*dto.MetricFamily, metricName string, value float64, timestampMS int64, hostname string, port string, extraLabels ...string) []*dto.MetricFamily {
+	metricDesc := plusMetricHelp[metricName]
+	if metricDesc == nil {
+		return metrics
+	}
+
+	return addMetric(metrics, metricName, metricDesc.help, metricDesc.metricType, value, timestampMS, hostname, port, extraLabels...)
+}
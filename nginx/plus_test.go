@@ -0,0 +1,110 @@
+package nginx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const plusStatusOk = `{
+  "connections": {"accepted": 1000, "dropped": 5, "active": 12, "idle": 8},
+  "http": {
+    "requests": {"total": 2000, "current": 3},
+    "server_zones": {
+      "example.com": {"requests": 1500, "responses": {"2xx": 1400, "4xx": 90, "5xx": 10}},
+      "other.com": {"requests": 200, "responses": {"2xx": 200}}
+    },
+    "upstreams": {
+      "backend": {"peers": [
+        {"server": "10.0.0.1:80", "state": "up", "active": 2, "requests": 900},
+        {"server": "10.0.0.2:80", "state": "down", "active": 0, "requests": 0}
+      ]}
+    },
+    "caches": {
+      "static": {"hit": {"responses": 300}, "miss": {"responses": 50}}
+    }
+  },
+  "ssl": {"handshakes": 42, "handshakes_failed": 1},
+  "resolvers": {
+    "dns": {"requests": {"name": 7}}
+  }
+}`
+
+func TestLoadPlusMetrics(t *testing.T) {
+	testServer := createPlusTestServer()
+	defer testServer.Close()
+
+	now := time.Now()
+	nowFn = func() time.Time { return now }
+
+	metrics, err := loadPlusMetrics(testServer.URL+"/api", hostname, port, scrapeOptsWithTimeout(10*time.Second))
+	require.NoError(t, err)
+	assert.NotEmpty(t, metrics)
+
+	byName := make(map[string]int, len(metrics))
+	for _, family := range metrics {
+		byName[family.GetName()]++
+	}
+
+	for _, expected := range []string{
+		"nginx_plus_connections_accepted",
+		"nginx_plus_requests_current",
+		"nginx_plus_server_zone_requests",
+		"nginx_plus_server_zone_responses",
+		"nginx_plus_upstream_peer_state",
+		"nginx_plus_cache_hits",
+		"nginx_plus_ssl_handshakes",
+		"nginx_plus_resolver_requests",
+	} {
+		// two server zones and two upstream peers are configured above, so every metric name
+		// must still collapse into a single family, not one family per zone/peer.
+		assert.Equal(t, 1, byName[expected], "expected exactly one family for metric %s", expected)
+	}
+
+	zoneFamily := findMetricFamily(metrics, "nginx_plus_server_zone_requests")
+	require.NotNil(t, zoneFamily)
+	require.Len(t, zoneFamily.Metric, 2)
+	assert.ElementsMatch(t, []string{"example.com", "other.com"}, plusLabelValues(zoneFamily, labelZone))
+
+	peerFamily := findMetricFamily(metrics, "nginx_plus_upstream_peer_state")
+	require.NotNil(t, peerFamily)
+	require.Len(t, peerFamily.Metric, 2)
+	assert.ElementsMatch(t, []string{"10.0.0.1:80", "10.0.0.2:80"}, plusLabelValues(peerFamily, labelPeer))
+}
+
+// plusLabelValues returns the labelName label value of every metric in family.
+func plusLabelValues(family *dto.MetricFamily, labelName string) []string {
+	values := make([]string, 0, len(family.Metric))
+	for _, metric := range family.Metric {
+		for _, label := range metric.Label {
+			if label.GetName() == labelName {
+				values = append(values, label.GetValue())
+			}
+		}
+	}
+	return values
+}
+
+func TestExtractPlusMetrics_InvalidJSON(t *testing.T) {
+	metrics, err := extractPlusMetrics([]byte("not json"), hostname, port)
+	require.Error(t, err)
+	require.Nil(t, metrics)
+}
+
+func createPlusTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.RequestURI, "/api/"+plusAPIVersion+"/") {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(plusStatusOk))
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("NOT FOUND"))
+		}
+	}))
+}
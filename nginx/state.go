@@ -0,0 +1,57 @@
+package nginx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// TargetState is the last-seen counter snapshot for one target, as persisted in a --state-file.
+type TargetState struct {
+	TimestampMS int64              `json:"timestamp_ms"`
+	Counters    map[string]float64 `json:"counters"`
+}
+
+// StateSnapshot is the on-disk shape of a --state-file: the last-seen counters per target, keyed
+// by "hostname:port", used to compute rate metrics between scrapes.
+type StateSnapshot struct {
+	Targets map[string]TargetState `json:"targets"`
+}
+
+// LoadState reads a state file written by SaveState. A missing file is not an error: it returns
+// an empty snapshot, since there is no previous state on the very first run.
+func LoadState(path string) (*StateSnapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &StateSnapshot{Targets: map[string]TargetState{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file: %s", err.Error())
+	}
+
+	var snapshot StateSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("error decoding state file: %s", err.Error())
+	}
+	if snapshot.Targets == nil {
+		snapshot.Targets = map[string]TargetState{}
+	}
+	return &snapshot, nil
+}
+
+// SaveState persists snapshot to path as JSON, for the next run to load via LoadState.
+func SaveState(path string, snapshot *StateSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("error encoding state file: %s", err.Error())
+	}
+	if err := ioutil.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("error writing state file: %s", err.Error())
+	}
+	return nil
+}
+
+func targetKey(hostname string, port string) string {
+	return hostname + ":" + port
+}
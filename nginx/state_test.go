@@ -0,0 +1,40 @@
+package nginx
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadState_MissingFile(t *testing.T) {
+	snapshot, err := LoadState(filepath.Join(t.TempDir(), "no-such-file.json"))
+	require.NoError(t, err)
+	assert.Empty(t, snapshot.Targets)
+}
+
+func TestSaveAndLoadState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	saved := &StateSnapshot{
+		Targets: map[string]TargetState{
+			"myhost:80": {TimestampMS: 1000, Counters: map[string]float64{"nginx_accepts": 5}},
+		},
+	}
+	require.NoError(t, SaveState(path, saved))
+
+	loaded, err := LoadState(path)
+	require.NoError(t, err)
+	assert.Equal(t, saved, loaded)
+}
+
+func TestLoadState_InvalidContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte("not json"), 0o600))
+
+	snapshot, err := LoadState(path)
+	require.Error(t, err)
+	require.Nil(t, snapshot)
+}
@@ -0,0 +1,108 @@
+package nginx
+
+import (
+	"sync"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// DefaultConcurrency bounds how many targets are scraped at once when no concurrency limit is
+// configured.
+const DefaultConcurrency = 5
+
+var (
+	upHelp              = "whether the last scrape of this target succeeded (1) or failed (0)"
+	scrapeDurationHelp  = "how long the last scrape of this target took, in seconds"
+	metricNginxUp       = "nginx_up"
+	metricScrapeSeconds = "nginx_scrape_duration_seconds"
+)
+
+// Target identifies a single NGINX instance to scrape: the status URL to request, plus the
+// host/port labels to apply to its metrics.
+type Target struct {
+	URL      string
+	Hostname string
+	Port     string
+}
+
+// GetMetricsForTargets concurrently scrapes every target, bounded by concurrency, and merges the
+// resulting metrics into a single stream. Every target also contributes a nginx_up{host,port}
+// gauge (1 on success, 0 on failure) and a nginx_scrape_duration_seconds{host,port} gauge,
+// mirroring the pattern used by mature Prometheus exporters, so a scrape failure on one target
+// does not prevent the others from being reported. Since every target reports the same metric
+// names (nginx_up, nginx_active, ...), same-named families across targets are merged into one,
+// rather than simply concatenated, so each metric name appears in the output exactly once.
+func GetMetricsForTargets(targets []Target, opts ScrapeOptions, concurrency int) []*dto.MetricFamily {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	results := make([][]*dto.MetricFamily, len(targets))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, target Target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = scrapeTarget(target, opts)
+		}(i, target)
+	}
+	wg.Wait()
+
+	metrics := make([]*dto.MetricFamily, 0, len(targets)*2)
+	for _, result := range results {
+		metrics = mergeMetricFamilies(metrics, result)
+	}
+
+	return metrics
+}
+
+// mergeMetricFamilies appends src onto dst, merging any family in src into the existing family of
+// the same name in dst rather than appending a duplicate.
+func mergeMetricFamilies(dst []*dto.MetricFamily, src []*dto.MetricFamily) []*dto.MetricFamily {
+	for _, family := range src {
+		if existing := findMetricFamily(dst, family.GetName()); existing != nil {
+			existing.Metric = append(existing.Metric, family.Metric...)
+			continue
+		}
+		dst = append(dst, family)
+	}
+	return dst
+}
+
+func scrapeTarget(target Target, opts ScrapeOptions) []*dto.MetricFamily {
+	start := nowFn()
+	metrics, err := GetMetrics(target.URL, target.Hostname, target.Port, opts)
+	durationSeconds := nowFn().Sub(start).Seconds()
+
+	up := float64(1)
+	if err != nil {
+		up = 0
+		metrics = nil
+	}
+
+	metrics = append(metrics, buildTargetGauge(metricNginxUp, upHelp, up, target, opts.Alias))
+	metrics = append(metrics, buildTargetGauge(metricScrapeSeconds, scrapeDurationHelp, durationSeconds, target, opts.Alias))
+
+	return metrics
+}
+
+func buildTargetGauge(name string, help string, value float64, target Target, alias string) *dto.MetricFamily {
+	nowMS := nowFn().UnixMilli()
+	labels := []*dto.LabelPair{{Name: &labelHost, Value: &target.Hostname}, {Name: &labelPort, Value: &target.Port}}
+	if alias != "" {
+		labels = append(labels, &dto.LabelPair{Name: &labelAlias, Value: &alias})
+	}
+
+	metricType := dto.MetricType_GAUGE
+	metric := &dto.Metric{
+		Label:       labels,
+		Gauge:       &dto.Gauge{Value: &value},
+		TimestampMs: &nowMS,
+	}
+
+	return &dto.MetricFamily{Name: &name, Help: &help, Type: &metricType, Metric: []*dto.Metric{metric}}
+}
@@ -0,0 +1,77 @@
+package nginx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetricsForTargets(t *testing.T) {
+	goodServer := createTestServer()
+	defer goodServer.Close()
+
+	now := time.Now()
+	nowFn = func() time.Time { return now }
+
+	targets := []Target{
+		{URL: goodServer.URL + "/nginx_status", Hostname: "good.sensu.local", Port: "1"},
+		{URL: "http://127.0.0.1:1/nginx_status", Hostname: "bad.sensu.local", Port: "2"},
+	}
+
+	opts := scrapeOptsWithTimeout(2 * time.Second)
+	opts.Mode, opts.Format = ModeStub, FormatStub
+
+	metrics := GetMetricsForTargets(targets, opts, 2)
+	require.NotEmpty(t, metrics)
+
+	byName := make(map[string]int, len(metrics))
+	for _, family := range metrics {
+		byName[family.GetName()]++
+	}
+
+	upByHost := make(map[string]float64)
+	var sawStubMetric bool
+	for _, family := range metrics {
+		if family.GetName() == "nginx_active" {
+			sawStubMetric = true
+		}
+		if family.GetName() != metricNginxUp {
+			continue
+		}
+		for _, metric := range family.Metric {
+			for _, label := range metric.Label {
+				if label.GetName() == labelHost {
+					upByHost[label.GetValue()] = metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+
+	assert.True(t, sawStubMetric, "expected metrics from the successful target to be merged in")
+	assert.Equal(t, float64(1), upByHost["good.sensu.local"])
+	assert.Equal(t, float64(0), upByHost["bad.sensu.local"])
+
+	// every target reports nginx_up, so the metric name must collapse into a single family with
+	// one Metric entry per target, not one family per target.
+	for _, name := range []string{metricNginxUp, "nginx_active"} {
+		assert.Equal(t, 1, byName[name], "expected exactly one family for metric %s", name)
+	}
+
+	upFamily := findMetricFamily(metrics, metricNginxUp)
+	require.NotNil(t, upFamily)
+	assert.Len(t, upFamily.Metric, len(targets))
+
+	activeFamily := findMetricFamily(metrics, "nginx_active")
+	require.NotNil(t, activeFamily)
+	assert.Len(t, activeFamily.Metric, 1, "only the successful target reports nginx_active")
+}
+
+func TestGetMetricsForTargets_NoTargets(t *testing.T) {
+	opts := scrapeOptsWithTimeout(time.Second)
+	opts.Mode, opts.Format = ModeStub, FormatStub
+
+	metrics := GetMetricsForTargets(nil, opts, 0)
+	assert.Empty(t, metrics)
+}
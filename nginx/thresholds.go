@@ -0,0 +1,85 @@
+package nginx
+
+import (
+	"fmt"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Severity indicates how a threshold breach should affect the check result.
+type Severity int
+
+const (
+	// SeverityWarning maps to sensu.CheckStateWarning.
+	SeverityWarning Severity = iota
+	// SeverityCritical maps to sensu.CheckStateCritical.
+	SeverityCritical
+)
+
+// Thresholds are the optional limits this check can enforce against the scraped metrics. A nil
+// field means that particular check is disabled.
+type Thresholds struct {
+	MaxActive       *float64
+	MinRequestsRate *float64
+	MaxWaitingRatio *float64
+}
+
+// Violation describes a single threshold breach, for one target.
+type Violation struct {
+	Hostname string
+	Port     string
+	Message  string
+	Severity Severity
+}
+
+// EvaluateThresholds checks metrics against thresholds and returns one Violation per breach
+// found, across every target present in metrics. MaxActive and MaxWaitingRatio are read straight
+// off the scraped nginx_active/nginx_waiting gauges; MinRequestsRate is read off the
+// nginx_requests_rate gauge added by AddDerivedMetrics, so it only fires once a --state-file has
+// a previous snapshot to compute a rate against.
+func EvaluateThresholds(metrics []*dto.MetricFamily, thresholds Thresholds) []Violation {
+	var violations []Violation
+
+	for _, target := range targetsIn(metrics) {
+		values := counterValues(metrics, target.hostname, target.port)
+
+		if thresholds.MaxActive != nil {
+			if active, ok := values["nginx_active"]; ok && active > *thresholds.MaxActive {
+				violations = append(violations, Violation{
+					Hostname: target.hostname,
+					Port:     target.port,
+					Message:  fmt.Sprintf("active connections %.0f exceed max-active %.0f", active, *thresholds.MaxActive),
+					Severity: SeverityCritical,
+				})
+			}
+		}
+
+		if thresholds.MinRequestsRate != nil {
+			if rate, ok := values["nginx_requests_rate"]; ok && rate < *thresholds.MinRequestsRate {
+				violations = append(violations, Violation{
+					Hostname: target.hostname,
+					Port:     target.port,
+					Message:  fmt.Sprintf("requests rate %.2f is below min-requests-rate %.2f", rate, *thresholds.MinRequestsRate),
+					Severity: SeverityWarning,
+				})
+			}
+		}
+
+		if thresholds.MaxWaitingRatio != nil {
+			active, hasActive := values["nginx_active"]
+			waiting, hasWaiting := values["nginx_waiting"]
+			if hasActive && hasWaiting && active > 0 {
+				if ratio := waiting / active; ratio > *thresholds.MaxWaitingRatio {
+					violations = append(violations, Violation{
+						Hostname: target.hostname,
+						Port:     target.port,
+						Message:  fmt.Sprintf("waiting ratio %.2f exceeds max-waiting-ratio %.2f", ratio, *thresholds.MaxWaitingRatio),
+						Severity: SeverityWarning,
+					})
+				}
+			}
+		}
+	}
+
+	return violations
+}
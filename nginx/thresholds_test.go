@@ -0,0 +1,58 @@
+package nginx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateThresholds(t *testing.T) {
+	now := time.Now()
+	nowFn = func() time.Time { return now }
+
+	metrics, err := extractMetrics([]byte(allLinesOk), hostname, port)
+	require.NoError(t, err)
+
+	t.Run("no thresholds configured", func(t *testing.T) {
+		assert.Empty(t, EvaluateThresholds(metrics, Thresholds{}))
+	})
+
+	t.Run("max-active breached", func(t *testing.T) {
+		maxActive := activeValue - 1
+		violations := EvaluateThresholds(metrics, Thresholds{MaxActive: &maxActive})
+		require.Len(t, violations, 1)
+		assert.Equal(t, SeverityCritical, violations[0].Severity)
+		assert.Equal(t, hostname, violations[0].Hostname)
+	})
+
+	t.Run("max-active within bounds", func(t *testing.T) {
+		maxActive := activeValue + 1
+		assert.Empty(t, EvaluateThresholds(metrics, Thresholds{MaxActive: &maxActive}))
+	})
+
+	t.Run("max-waiting-ratio breached", func(t *testing.T) {
+		maxRatio := (waitingValue / activeValue) - 0.01
+		violations := EvaluateThresholds(metrics, Thresholds{MaxWaitingRatio: &maxRatio})
+		require.Len(t, violations, 1)
+		assert.Equal(t, SeverityWarning, violations[0].Severity)
+	})
+
+	t.Run("min-requests-rate breached once derived", func(t *testing.T) {
+		previous := &StateSnapshot{
+			Targets: map[string]TargetState{
+				targetKey(hostname, port): {
+					TimestampMS: now.Add(-10 * time.Second).UnixMilli(),
+					Counters:    map[string]float64{"nginx_accepts": acceptsValue, "nginx_handled": handledValue, "nginx_requests": requestsValue - 5},
+				},
+			},
+		}
+		extended, _ := AddDerivedMetrics(metrics, "", previous, now.UnixMilli())
+
+		minRate := float64(1)
+		violations := EvaluateThresholds(extended, Thresholds{MinRequestsRate: &minRate})
+		require.Len(t, violations, 1)
+		assert.Equal(t, SeverityWarning, violations[0].Severity)
+	})
+}
@@ -0,0 +1,80 @@
+package nginx
+
+import (
+	"encoding/json"
+	"fmt"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+var vtsMetricHelp = map[string]*MetricDesc{
+	"nginx_vts_server_zone_requests":   {"requests handled by the VTS server zone", dto.MetricType_COUNTER},
+	"nginx_vts_server_zone_responses":  {"responses sent by the VTS server zone, by status code class", dto.MetricType_COUNTER},
+	"nginx_vts_upstream_zone_requests": {"requests handled by the VTS upstream zone server", dto.MetricType_COUNTER},
+	"nginx_vts_cache_zone_responses":   {"responses served by the VTS cache zone, by cache status", dto.MetricType_COUNTER},
+	"nginx_vts_filter_zone_requests":   {"requests matched by the VTS filter zone", dto.MetricType_COUNTER},
+}
+
+// vtsStatus mirrors the subset of the nginx-vts-module status JSON output used by this check.
+type vtsStatus struct {
+	ServerZones map[string]struct {
+		RequestCounter uint64            `json:"requestCounter"`
+		Responses      map[string]uint64 `json:"responses"`
+	} `json:"serverZones"`
+	UpstreamZones map[string][]struct {
+		Server         string `json:"server"`
+		RequestCounter uint64 `json:"requestCounter"`
+	} `json:"upstreamZones"`
+	CacheZones map[string]struct {
+		Responses map[string]uint64 `json:"responses"`
+	} `json:"cacheZones"`
+	FilterZones map[string]struct {
+		RequestCounter uint64 `json:"requestCounter"`
+	} `json:"filterZones"`
+}
+
+// extractVTSMetrics converts a nginx-vts-module status JSON payload into Prometheus metrics.
+func extractVTSMetrics(content []byte, hostname string, port string) ([]*dto.MetricFamily, error) {
+	var status vtsStatus
+	if err := json.Unmarshal(content, &status); err != nil {
+		return nil, fmt.Errorf("error decoding nginx vts status payload: %s", err.Error())
+	}
+
+	nowMS := nowFn().UnixMilli()
+	metrics := make([]*dto.MetricFamily, 0, 8)
+
+	for zone, data := range status.ServerZones {
+		metrics = addVTSMetric(metrics, "nginx_vts_server_zone_requests", float64(data.RequestCounter), nowMS, hostname, port, labelZone, zone)
+		for code, count := range data.Responses {
+			metrics = addVTSMetric(metrics, "nginx_vts_server_zone_responses", float64(count), nowMS, hostname, port, labelZone, zone, "code", code)
+		}
+	}
+
+	for zone, peers := range status.UpstreamZones {
+		for _, peer := range peers {
+			metrics = addVTSMetric(metrics, "nginx_vts_upstream_zone_requests", float64(peer.RequestCounter), nowMS, hostname, port, labelUpstream, zone, labelPeer, peer.Server)
+		}
+	}
+
+	for zone, data := range status.CacheZones {
+		for cacheStatus, count := range data.Responses {
+			metrics = addVTSMetric(metrics, "nginx_vts_cache_zone_responses", float64(count), nowMS, hostname, port, labelZone, zone, "status", cacheStatus)
+		}
+	}
+
+	for zone, data := range status.FilterZones {
+		metrics = addVTSMetric(metrics, "nginx_vts_filter_zone_requests", float64(data.RequestCounter), nowMS, hostname, port, labelZone, zone)
+	}
+
+	return metrics, nil
+}
+
+// addVTSMetric appends a metric for the given VTS metric name, via the shared addMetric helper.
+func addVTSMetric(metrics []*dto.MetricFamily, metricName string, value float64, timestampMS int64, hostname string, port string, extraLabels ...string) []*dto.MetricFamily {
+	metricDesc := vtsMetricHelp[metricName]
+	if metricDesc == nil {
+		return metrics
+	}
+
+	return addMetric(metrics, metricName, metricDesc.help, metricDesc.metricType, value, timestampMS, hostname, port, extraLabels...)
+}
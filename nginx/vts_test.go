@@ -0,0 +1,96 @@
+package nginx
+
+import (
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const vtsStatusOk = `{
+  "serverZones": {
+    "example.com": {"requestCounter": 10, "responses": {"2xx": 8, "4xx": 1, "5xx": 1}},
+    "other.com": {"requestCounter": 3, "responses": {"2xx": 3}}
+  },
+  "upstreamZones": {
+    "backend": [
+      {"server": "10.0.0.1:80", "requestCounter": 6},
+      {"server": "10.0.0.2:80", "requestCounter": 2}
+    ]
+  },
+  "cacheZones": {
+    "static": {"responses": {"hit": 9, "miss": 1}}
+  },
+  "filterZones": {
+    "country::US": {"requestCounter": 4}
+  }
+}`
+
+func TestExtractVTSMetrics(t *testing.T) {
+	now := time.Now()
+	nowFn = func() time.Time { return now }
+
+	metrics, err := extractVTSMetrics([]byte(vtsStatusOk), hostname, port)
+	require.NoError(t, err)
+
+	byName := make(map[string]int, len(metrics))
+	for _, family := range metrics {
+		byName[family.GetName()]++
+	}
+
+	for _, expected := range []string{
+		"nginx_vts_server_zone_requests",
+		"nginx_vts_server_zone_responses",
+		"nginx_vts_upstream_zone_requests",
+		"nginx_vts_cache_zone_responses",
+		"nginx_vts_filter_zone_requests",
+	} {
+		// two server zones and two upstream peers are configured above, so every metric name
+		// must still collapse into a single family, not one family per zone/peer.
+		assert.Equal(t, 1, byName[expected], "expected exactly one family for metric %s", expected)
+	}
+
+	requestsFamily := findMetricFamily(metrics, "nginx_vts_server_zone_requests")
+	require.NotNil(t, requestsFamily)
+	require.Len(t, requestsFamily.Metric, 2)
+	assert.ElementsMatch(t, []string{"example.com", "other.com"}, vtsZoneLabels(requestsFamily))
+
+	upstreamFamily := findMetricFamily(metrics, "nginx_vts_upstream_zone_requests")
+	require.NotNil(t, upstreamFamily)
+	require.Len(t, upstreamFamily.Metric, 2)
+	assert.ElementsMatch(t, []string{"10.0.0.1:80", "10.0.0.2:80"}, vtsPeerLabels(upstreamFamily))
+}
+
+// vtsZoneLabels returns the "zone" label value of every metric in family.
+func vtsZoneLabels(family *dto.MetricFamily) []string {
+	zones := make([]string, 0, len(family.Metric))
+	for _, metric := range family.Metric {
+		for _, label := range metric.Label {
+			if label.GetName() == labelZone {
+				zones = append(zones, label.GetValue())
+			}
+		}
+	}
+	return zones
+}
+
+// vtsPeerLabels returns the "peer" label value of every metric in family.
+func vtsPeerLabels(family *dto.MetricFamily) []string {
+	peers := make([]string, 0, len(family.Metric))
+	for _, metric := range family.Metric {
+		for _, label := range metric.Label {
+			if label.GetName() == labelPeer {
+				peers = append(peers, label.GetValue())
+			}
+		}
+	}
+	return peers
+}
+
+func TestExtractVTSMetrics_InvalidJSON(t *testing.T) {
+	metrics, err := extractVTSMetrics([]byte("not json"), hostname, port)
+	require.Error(t, err)
+	require.Nil(t, metrics)
+}